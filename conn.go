@@ -30,6 +30,7 @@ type conn struct {
 	conn         net.Conn
 	bw           *bufio.Writer
 	writeTimeout time.Duration
+	idleTime     time.Time
 }
 
 // NewConn returns a new connection for the given net connection.
@@ -69,6 +70,21 @@ func (c *conn) Err() error {
 	return err
 }
 
+// MarkIdleTime records that the connection just became idle.
+func (c *conn) MarkIdleTime() {
+	c.mu.Lock()
+	c.idleTime = time.Now()
+	c.mu.Unlock()
+}
+
+// GetIdleTime returns the time MarkIdleTime was last called.
+func (c *conn) GetIdleTime() time.Time {
+	c.mu.Lock()
+	idleTime := c.idleTime
+	c.mu.Unlock()
+	return idleTime
+}
+
 func (c *conn) Pub(topic string, message []byte) error {
 	if len(topic) == 0 || message == nil {
 		return c.fatal(errors.New("topic and message should not be empty"))
@@ -92,3 +108,27 @@ func (c *conn) Pub(topic string, message []byte) error {
 
 	return nil
 }
+
+func (c *conn) MPub(topic string, messages [][]byte) error {
+	if len(topic) == 0 || len(messages) == 0 {
+		return c.fatal(errors.New("topic and messages should not be empty"))
+	}
+
+	if c.writeTimeout != 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+
+	log.Println(topic, len(messages), "messages")
+
+	for _, message := range messages {
+		if _, err := c.bw.Write(message); err != nil {
+			return c.fatal(err)
+		}
+	}
+
+	if err := c.bw.Flush(); err != nil {
+		return c.fatal(err)
+	}
+
+	return nil
+}
@@ -100,22 +100,22 @@ func (c *loggingConn) debug(method string, commandName []byte, args []interface{
 	c.logger.Output(3, buf.String())
 }
 
-func (c *loggingConn) Post(command []byte, args ...interface{}) (interface{}, error) {
-	response, err := c.Conn.Post(command, args...)
-	c.debug("Do", command, args, response, err)
-	return response, err
-}
-
-func (c *loggingConn) Send(command []byte, args ...interface{}) error {
-	err := c.Conn.Send(command, args...)
-	c.debug("Send", command, args, nil, err)
+func (c *loggingConn) Pub(topic string, message []byte) error {
+	err := c.Conn.Pub(topic, message)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%sPub(%q, ", c.prefix, topic)
+	c.printValue(&buf, message)
+	fmt.Fprintf(&buf, ") -> (%v)", err)
+	c.logger.Output(2, buf.String())
 	return err
 }
 
-func (c *loggingConn) Receive() (interface{}, error) {
-	reply, err := c.Conn.Receive()
-	c.debug("Receive", []byte(""), nil, reply, err)
-	return reply, err
+func (c *loggingConn) MPub(topic string, messages [][]byte) error {
+	err := c.Conn.MPub(topic, messages)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%sMPub(%q, %d messages) -> (%v)", c.prefix, topic, len(messages), err)
+	c.logger.Output(2, buf.String())
+	return err
 }
 
 // MarkIdleTime mark time becoming idle.
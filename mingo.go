@@ -1,5 +1,7 @@
 package mingo
 
+import "time"
+
 // Error represents an error returned in a command reply.
 type Error string
 
@@ -15,4 +17,16 @@ type Conn interface {
 
 	// Pub a message to the server
 	Pub(topic string,message []byte)     error
+
+	// MPub publishes several messages to topic as a single batch: each
+	// message is written to the connection's buffer in turn and the buffer
+	// is flushed once.
+	MPub(topic string, messages [][]byte) error
+
+	// MarkIdleTime records that the connection just became idle, for use by
+	// Pool when it returns the connection to the idle list.
+	MarkIdleTime()
+
+	// GetIdleTime returns the time MarkIdleTime was last called.
+	GetIdleTime() time.Time
 }
@@ -0,0 +1,228 @@
+package mingo
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoHealthyEndpoint is returned by MultiPool.Get, GetContext and Publish
+// when every endpoint is either marked unhealthy or the MultiPool has none
+// configured.
+var ErrNoHealthyEndpoint = errors.New("mingo: no healthy endpoint")
+
+// Selector picks one of the given pools to serve an operation. pools and
+// healthy are parallel slices of the same length; healthy[i] is false for
+// endpoints MultiPool has temporarily marked down after a Publish failure.
+// Select returns -1 if no healthy pool is available. topic is the NSQ topic
+// being published to, or "" for operations with no topic (Get, GetContext).
+// Implementations must be safe for concurrent use.
+type Selector interface {
+	Select(pools []*Pool, healthy []bool, topic string) int
+}
+
+// firstHealthyFrom returns the first healthy index at or after start,
+// wrapping around pools once. It returns -1 if none are healthy, including
+// when healthy is empty.
+func firstHealthyFrom(healthy []bool, start int) int {
+	n := len(healthy)
+	if n == 0 {
+		return -1
+	}
+	for i := 0; i < n; i++ {
+		idx := (start + i) % n
+		if healthy[idx] {
+			return idx
+		}
+	}
+	return -1
+}
+
+// RoundRobinSelector cycles through pools in order, skipping unhealthy ones.
+// The zero value is ready to use.
+type RoundRobinSelector struct {
+	next uint64
+}
+
+// Select implements Selector.
+func (s *RoundRobinSelector) Select(pools []*Pool, healthy []bool, topic string) int {
+	if len(pools) == 0 {
+		return -1
+	}
+	start := int(atomic.AddUint64(&s.next, 1)-1) % len(pools)
+	return firstHealthyFrom(healthy, start)
+}
+
+// RandomSelector picks a uniformly random pool, skipping unhealthy ones.
+type RandomSelector struct{}
+
+// Select implements Selector.
+func (RandomSelector) Select(pools []*Pool, healthy []bool, topic string) int {
+	if len(pools) == 0 {
+		return -1
+	}
+	return firstHealthyFrom(healthy, rand.Intn(len(pools)))
+}
+
+// LeastActiveSelector picks the healthy pool with the fewest active
+// connections, approximating the least-loaded endpoint.
+type LeastActiveSelector struct{}
+
+// Select implements Selector.
+func (LeastActiveSelector) Select(pools []*Pool, healthy []bool, topic string) int {
+	best := -1
+	bestActive := 0
+	for i, p := range pools {
+		if !healthy[i] {
+			continue
+		}
+		if active := p.ActiveCount(); best == -1 || active < bestActive {
+			best, bestActive = i, active
+		}
+	}
+	return best
+}
+
+// ConsistentHashSelector maps a topic to a stable shard by hashing its name,
+// so repeated publishes for the same topic land on the same endpoint as
+// long as it stays healthy. It falls back to the next healthy endpoint, in
+// order, when the chosen shard is down.
+type ConsistentHashSelector struct{}
+
+// Select implements Selector.
+func (ConsistentHashSelector) Select(pools []*Pool, healthy []bool, topic string) int {
+	if len(pools) == 0 {
+		return -1
+	}
+	h := fnv.New32a()
+	h.Write([]byte(topic))
+	start := int(h.Sum32() % uint32(len(pools)))
+	return firstHealthyFrom(healthy, start)
+}
+
+// Endpoint is one backing Pool in a MultiPool, addressed by the nsqd
+// address it was dialed against.
+type Endpoint struct {
+	Addr string
+	Pool *Pool
+}
+
+// MultiPool fans Get, GetContext and Publish out across several Pools, one
+// per nsqd endpoint, using a pluggable Selector to choose an endpoint per
+// operation. This matches NSQ's deployment model of many independent nsqd
+// nodes with no single master, letting Conn.Pub be used against a cluster
+// without callers writing their own fanout/failover logic.
+type MultiPool struct {
+	// Selector chooses which endpoint serves each operation.
+	Selector Selector
+
+	// Cooldown is how long a Publish failure marks its endpoint unhealthy
+	// and skips it in selection. Zero disables marking endpoints down.
+	Cooldown time.Duration
+
+	endpoints []Endpoint
+
+	mu        sync.Mutex
+	downUntil []time.Time
+}
+
+// NewMultiPool creates a MultiPool backed by endpoints. selector defaults to
+// a *RoundRobinSelector when nil.
+func NewMultiPool(endpoints []Endpoint, selector Selector, cooldown time.Duration) *MultiPool {
+	if selector == nil {
+		selector = &RoundRobinSelector{}
+	}
+	return &MultiPool{
+		Selector:  selector,
+		Cooldown:  cooldown,
+		endpoints: endpoints,
+		downUntil: make([]time.Time, len(endpoints)),
+	}
+}
+
+// snapshot builds the parallel pools/healthy slices Selector.Select expects,
+// treating an endpoint as healthy again once its cooldown has elapsed.
+func (mp *MultiPool) snapshot() ([]*Pool, []bool) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	pools := make([]*Pool, len(mp.endpoints))
+	healthy := make([]bool, len(mp.endpoints))
+	now := nowFunc()
+	for i, ep := range mp.endpoints {
+		pools[i] = ep.Pool
+		healthy[i] = mp.downUntil[i].IsZero() || !now.Before(mp.downUntil[i])
+	}
+	return pools, healthy
+}
+
+// markUnhealthy marks the endpoint at idx down for Cooldown.
+func (mp *MultiPool) markUnhealthy(idx int) {
+	if mp.Cooldown <= 0 {
+		return
+	}
+	mp.mu.Lock()
+	mp.downUntil[idx] = nowFunc().Add(mp.Cooldown)
+	mp.mu.Unlock()
+}
+
+// Get picks an endpoint via Selector and returns a connection from its
+// pool. It is equivalent to calling GetContext with context.Background().
+func (mp *MultiPool) Get() (Conn, error) {
+	return mp.GetContext(context.Background())
+}
+
+// GetContext is the context-aware equivalent of Get.
+func (mp *MultiPool) GetContext(ctx context.Context) (Conn, error) {
+	pools, healthy := mp.snapshot()
+	if len(pools) == 0 {
+		return nil, ErrNoHealthyEndpoint
+	}
+	idx := mp.Selector.Select(pools, healthy, "")
+	if idx < 0 {
+		return nil, ErrNoHealthyEndpoint
+	}
+	return pools[idx].GetContext(ctx)
+}
+
+// Publish picks an endpoint for topic via Selector and publishes msg to it,
+// returning the connection to that endpoint's pool afterwards. On failure
+// to dial or publish, the endpoint is marked unhealthy for Cooldown.
+func (mp *MultiPool) Publish(topic string, msg []byte) error {
+	pools, healthy := mp.snapshot()
+	if len(pools) == 0 {
+		return ErrNoHealthyEndpoint
+	}
+	idx := mp.Selector.Select(pools, healthy, topic)
+	if idx < 0 {
+		return ErrNoHealthyEndpoint
+	}
+
+	c, err := pools[idx].GetContext(context.Background())
+	if err != nil {
+		mp.markUnhealthy(idx)
+		return err
+	}
+
+	pubErr := c.Pub(topic, msg)
+	pools[idx].Put(c, pubErr != nil)
+	if pubErr != nil {
+		mp.markUnhealthy(idx)
+	}
+	return pubErr
+}
+
+// Close closes every endpoint's pool, returning the first error encountered.
+func (mp *MultiPool) Close() error {
+	var first error
+	for _, ep := range mp.endpoints {
+		if err := ep.Pool.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
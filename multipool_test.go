@@ -0,0 +1,152 @@
+package mingo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func allSelectors() []Selector {
+	return []Selector{
+		&RoundRobinSelector{},
+		RandomSelector{},
+		LeastActiveSelector{},
+		ConsistentHashSelector{},
+	}
+}
+
+func TestSelectorsReturnNegativeOneForEmptyPools(t *testing.T) {
+	for _, sel := range allSelectors() {
+		if idx := sel.Select(nil, nil, "topic"); idx != -1 {
+			t.Fatalf("%T.Select(nil, nil, ...) = %d, want -1", sel, idx)
+		}
+	}
+}
+
+func TestMultiPoolNoEndpointsReturnsErrNoHealthyEndpoint(t *testing.T) {
+	for _, sel := range allSelectors() {
+		mp := NewMultiPool(nil, sel, 0)
+
+		if _, err := mp.GetContext(context.Background()); err != ErrNoHealthyEndpoint {
+			t.Fatalf("%T: GetContext = %v, want ErrNoHealthyEndpoint", sel, err)
+		}
+		if err := mp.Publish("topic", []byte("msg")); err != ErrNoHealthyEndpoint {
+			t.Fatalf("%T: Publish = %v, want ErrNoHealthyEndpoint", sel, err)
+		}
+	}
+}
+
+func TestRoundRobinSelectorCycles(t *testing.T) {
+	pools := []*Pool{{}, {}, {}}
+	healthy := []bool{true, true, true}
+	sel := &RoundRobinSelector{}
+
+	got := make([]int, 6)
+	for i := range got {
+		got[i] = sel.Select(pools, healthy, "")
+	}
+	want := []int{0, 1, 2, 0, 1, 2}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Select sequence = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRoundRobinSelectorSkipsUnhealthy(t *testing.T) {
+	pools := []*Pool{{}, {}, {}}
+	healthy := []bool{true, false, true}
+	sel := &RoundRobinSelector{}
+
+	for i := 0; i < 4; i++ {
+		if idx := sel.Select(pools, healthy, ""); idx == 1 {
+			t.Fatal("Select returned unhealthy index 1")
+		}
+	}
+}
+
+func TestLeastActiveSelectorPicksFewestActive(t *testing.T) {
+	busy := &Pool{}
+	busy.active = 5
+	idle := &Pool{}
+	idle.active = 1
+	pools := []*Pool{busy, idle}
+	healthy := []bool{true, true}
+
+	sel := LeastActiveSelector{}
+	if idx := sel.Select(pools, healthy, ""); idx != 1 {
+		t.Fatalf("Select = %d, want 1 (the less-active pool)", idx)
+	}
+}
+
+func TestLeastActiveSelectorSkipsUnhealthy(t *testing.T) {
+	quiet := &Pool{}
+	busy := &Pool{}
+	busy.active = 5
+	pools := []*Pool{quiet, busy}
+	healthy := []bool{false, true}
+
+	sel := LeastActiveSelector{}
+	if idx := sel.Select(pools, healthy, ""); idx != 1 {
+		t.Fatalf("Select = %d, want 1 (the only healthy pool, even though it's busier)", idx)
+	}
+}
+
+func TestConsistentHashSelectorStablePerTopic(t *testing.T) {
+	pools := []*Pool{{}, {}, {}, {}}
+	healthy := []bool{true, true, true, true}
+	sel := ConsistentHashSelector{}
+
+	first := sel.Select(pools, healthy, "orders")
+	for i := 0; i < 10; i++ {
+		if idx := sel.Select(pools, healthy, "orders"); idx != first {
+			t.Fatalf("Select(%q) = %d on call %d, want stable %d", "orders", idx, i, first)
+		}
+	}
+}
+
+func TestConsistentHashSelectorFallsBackWhenShardUnhealthy(t *testing.T) {
+	pools := []*Pool{{}, {}, {}, {}}
+	healthy := []bool{true, true, true, true}
+	sel := ConsistentHashSelector{}
+
+	shard := sel.Select(pools, healthy, "orders")
+	healthy[shard] = false
+
+	idx := sel.Select(pools, healthy, "orders")
+	if idx == shard {
+		t.Fatalf("Select returned the now-unhealthy shard %d", shard)
+	}
+	if idx < 0 {
+		t.Fatalf("Select = %d, want a healthy fallback", idx)
+	}
+}
+
+func TestMultiPoolMarkUnhealthyExcludesThenRecovers(t *testing.T) {
+	realNow := nowFunc
+	defer func() { nowFunc = realNow }()
+
+	now := time.Now()
+	nowFunc = func() time.Time { return now }
+
+	mp := NewMultiPool([]Endpoint{
+		{Addr: "a", Pool: &Pool{}},
+		{Addr: "b", Pool: &Pool{}},
+	}, &RoundRobinSelector{}, time.Minute)
+
+	mp.markUnhealthy(0)
+
+	_, healthy := mp.snapshot()
+	if healthy[0] {
+		t.Fatal("endpoint 0 should be unhealthy right after markUnhealthy")
+	}
+	if !healthy[1] {
+		t.Fatal("endpoint 1 should be unaffected")
+	}
+
+	now = now.Add(time.Minute)
+	_, healthy = mp.snapshot()
+	if !healthy[0] {
+		t.Fatal("endpoint 0 should be healthy again once Cooldown has elapsed")
+	}
+}
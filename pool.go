@@ -16,8 +16,8 @@ package mingo
 
 import (
 	"container/list"
+	"context"
 	"errors"
-	"log"
 	"sync"
 	"time"
 )
@@ -29,6 +29,10 @@ var nowFunc = time.Now // for testing
 // pool has been reached.
 var ErrPoolExhausted = errors.New("mingo: connection pool exhausted")
 
+// ErrDialRateLimited is returned from Get/GetContext when MaxDialsPerSecond
+// is set, the pool has no idle connections, and Wait is false.
+var ErrDialRateLimited = errors.New("mingo: dial rate limited")
+
 var (
 	errPoolClosed = errors.New("mingo: connection pool closed")
 	errConnClosed = errors.New("mingo: connection closed")
@@ -108,6 +112,15 @@ var (
 //    },
 //  }
 //
+// Use GetContext instead of Get when a caller should stop waiting for a
+// connection once a deadline passes or the request is canceled:
+//
+//  conn, err := pool.GetContext(ctx)
+//  if err != nil {
+//    return err
+//  }
+//  defer pool.Put(conn, false)
+//
 type Pool struct {
 
 	// Dial is an application supplied function for creating and configuring a
@@ -131,14 +144,48 @@ type Pool struct {
 	// When zero, there is no limit on the number of connections in the pool.
 	MaxActive int
 
+	// MaxDialsPerSecond throttles how many new connections the pool may
+	// dial per second, so a burst of simultaneous Get calls against an empty
+	// idle list doesn't fire off a dial storm against the upstream server.
+	// Zero means unlimited.
+	MaxDialsPerSecond int
+
+	// BatchWindow, when nonzero, lets Publish coalesce concurrent calls for
+	// the same topic that arrive within the window into a single MPub.
+	// Zero makes Publish send every call immediately on its own.
+	BatchWindow time.Duration
+
+	// BatchSize caps how many messages a coalescing batch holds before it is
+	// flushed early, regardless of BatchWindow. Zero means no cap besides
+	// the window.
+	BatchSize int
+
 	// Close connections after remaining idle for this duration. If the value
 	// is zero, then idle connections are not closed. Applications should set
 	// the timeout to a value less than the server's timeout.
 	IdleTimeout time.Duration
 
-	// check idle conn and release timeout idle connections
+	// GCInterval is how often the background reaper runs, sweeping the idle
+	// list for connections that have exceeded IdleTimeout, when
+	// HealthCheckInterval is zero; a nonzero HealthCheckInterval takes its
+	// place. Defaults to one minute when both are zero and a reaper is needed.
 	GCInterval time.Duration
 
+	// MinIdle is the minimum number of idle connections the background
+	// reaper tries to keep warm by dialing ahead of demand. Zero means the
+	// pool only dials lazily from Get/GetContext.
+	MinIdle int
+
+	// HealthCheckInterval is how often the background reaper runs. Zero
+	// falls back to GCInterval, and then to one minute.
+	HealthCheckInterval time.Duration
+
+	// IdlePingTimeout is how long a connection may sit idle before the
+	// background reaper calls TestOnBorrow on it to proactively evict dead
+	// connections, rather than waiting for the connection to be borrowed
+	// again. Zero disables proactive pinging.
+	IdlePingTimeout time.Duration
+
 	// If Wait is true and the pool is at the MaxActive limit, then Get() waits
 	// for a connection to be returned to the pool before returning.
 	Wait bool
@@ -146,15 +193,53 @@ type Pool struct {
 	// mark pool initialized
 	initialized bool
 
-	// mark previous clean time
-	nextGCTime time.Time
-
 	// mu protects fields defined below.
 	mu     sync.Mutex
-	cond   *sync.Cond
 	closed bool
 	active int
 
+	// reaperStarted and stopReaper track the lazily-started background
+	// reaper goroutine; stopReaper is closed by Close to stop it.
+	reaperStarted bool
+	stopReaper    chan struct{}
+
+	// dialNotifierStarted and stopDialNotifier track the lazily-started
+	// goroutine that wakes dial-rate-limited waiters; stopDialNotifier is
+	// closed by Close to stop it.
+	dialNotifierStarted bool
+	stopDialNotifier    chan struct{}
+
+	// dialTokens and lastDial back the token bucket used by
+	// MaxDialsPerSecond. Both get and fillMinIdle call takeDialToken while
+	// holding mu, so these need no lock of their own.
+	dialTokens float64
+	lastDial   time.Time
+
+	// Cumulative counters surfaced through Stats; all guarded by mu.
+	waitCount            uint64
+	waitDuration         time.Duration
+	dialCount            uint64
+	dialErrorCount       uint64
+	timeoutCount         uint64
+	testOnBorrowFailures uint64
+
+	// batchMu protects pending per-topic batches used by Publish.
+	batchMu sync.Mutex
+	batches map[string]*pubBatch
+
+	// FIFO queue of waiters blocked in GetContext because the pool is at
+	// MaxActive. Each element is a chan struct{} with capacity 1; put and
+	// release wake waiters by popping the front of the queue and sending on
+	// its channel.
+	waiters list.List
+
+	// dialWaiters is a second FIFO queue, parallel to waiters, for callers
+	// blocked in get() on the MaxDialsPerSecond token bucket rather than on
+	// MaxActive. It is kept separate so the dial notifier's periodic wakeup
+	// can never steal a turn from a plain MaxActive waiter sitting at the
+	// front of waiters, which would break that queue's FIFO ordering.
+	dialWaiters list.List
+
 	// Stack of idleConn with most recently used at the front.
 	idle list.List
 }
@@ -163,7 +248,7 @@ type Pool struct {
 //
 // Deprecated: Initialize the Pool directory as shown in the example.
 func NewPool(newFn func() (Conn, error), maxIdle int) *Pool {
-	return &Pool{Dial: newFn, MaxIdle: maxIdle, nextGCTime: nowFunc()}
+	return &Pool{Dial: newFn, MaxIdle: maxIdle}
 }
 
 // Get gets a connection. The application must close the returned connection.
@@ -171,14 +256,27 @@ func NewPool(newFn func() (Conn, error), maxIdle int) *Pool {
 // error handling to the first use of the connection. If there is an error
 // getting an underlying connection, then the connection Err, Do, Send, Flush
 // and Receive methods return that error.
+//
+// Get is equivalent to calling GetContext with context.Background(), so it
+// can never be canceled while waiting for a connection to become available.
 func (p *Pool) Get() Conn {
-	c, err := p.get()
+	c, err := p.GetContext(context.Background())
 	if err != nil {
 		return errorConnection{err: err}
 	}
 	return c
 }
 
+// GetContext gets a connection, honoring ctx's deadline and cancellation
+// while waiting for one to become available. Unlike Get, GetContext returns
+// a nil Conn and the context's error instead of an errorConnection when ctx
+// is done before a connection can be obtained; a caller that already holds a
+// waiting slot is removed from the wait queue cleanly and no connection is
+// leaked.
+func (p *Pool) GetContext(ctx context.Context) (Conn, error) {
+	return p.get(ctx)
+}
+
 // ActiveCount returns the number of active connections in the pool.
 func (p *Pool) ActiveCount() int {
 	p.mu.Lock()
@@ -195,6 +293,53 @@ func (p *Pool) IdleCount() int {
 	return idle
 }
 
+// PoolStats holds point-in-time and cumulative counters describing pool
+// activity. Operators use these to size MaxActive/MaxIdle and to spot
+// connection churn that ActiveCount and IdleCount alone can't show.
+type PoolStats struct {
+	// ActiveCount and IdleCount are point-in-time counts, as returned by the
+	// methods of the same name.
+	ActiveCount int
+	IdleCount   int
+
+	// WaitCount is the cumulative number of Get/GetContext calls that
+	// blocked because the pool was at MaxActive or dial rate limited.
+	WaitCount uint64
+
+	// WaitDuration is the cumulative time spent blocked across all such
+	// calls.
+	WaitDuration time.Duration
+
+	// DialCount and DialErrorCount are the cumulative number of dial
+	// attempts and the subset of those that returned an error.
+	DialCount      uint64
+	DialErrorCount uint64
+
+	// TimeoutCount is the cumulative number of idle connections evicted for
+	// exceeding IdleTimeout.
+	TimeoutCount uint64
+
+	// TestOnBorrowFailures is the cumulative number of connections closed
+	// because TestOnBorrow reported them unhealthy.
+	TestOnBorrowFailures uint64
+}
+
+// Stats returns a snapshot of the pool's counters.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PoolStats{
+		ActiveCount:          p.active,
+		IdleCount:            p.idle.Len(),
+		WaitCount:            p.waitCount,
+		WaitDuration:         p.waitDuration,
+		DialCount:            p.dialCount,
+		DialErrorCount:       p.dialErrorCount,
+		TimeoutCount:         p.timeoutCount,
+		TestOnBorrowFailures: p.testOnBorrowFailures,
+	}
+}
+
 // Close releases the resources used by the pool.
 func (p *Pool) Close() error {
 	p.mu.Lock()
@@ -202,60 +347,213 @@ func (p *Pool) Close() error {
 	p.idle.Init()
 	p.closed = true
 	p.active -= idle.Len()
-	if p.cond != nil {
-		p.cond.Broadcast()
+	p.wakeAllLocked()
+	if p.reaperStarted {
+		close(p.stopReaper)
+		p.reaperStarted = false
+	}
+	if p.dialNotifierStarted {
+		close(p.stopDialNotifier)
+		p.dialNotifierStarted = false
 	}
 	p.mu.Unlock()
+
+	p.cancelPendingBatches()
+
 	for e := idle.Front(); e != nil; e = e.Next() {
 		e.Value.(Conn).Close()
 	}
 	return nil
 }
 
-// release decrements the active count and signals waiters. The caller must
-// hold p.mu during the call.
+// cancelPendingBatches stops the timer on every batch still waiting out its
+// BatchWindow and fails its callers with errPoolClosed, so Close doesn't
+// leave a Publish call blocked until the window elapses on its own.
+func (p *Pool) cancelPendingBatches() {
+	p.batchMu.Lock()
+	batches := p.batches
+	p.batches = nil
+	p.batchMu.Unlock()
+
+	for _, b := range batches {
+		b.timer.Stop()
+		for _, done := range b.done {
+			done <- errPoolClosed
+		}
+	}
+}
+
+// release decrements the active count and wakes a waiter, if any. The caller
+// must hold p.mu during the call.
 func (p *Pool) release() {
 	p.active--
-	if p.cond != nil {
-		p.cond.Signal()
+	p.wakeOneLocked()
+}
+
+// wakeFrontLocked wakes the longest-waiting entry in q, if any, and reports
+// whether it woke someone. The caller must hold p.mu during the call.
+func wakeFrontLocked(q *list.List) bool {
+	e := q.Front()
+	if e == nil {
+		return false
 	}
+	q.Remove(e)
+	e.Value.(chan struct{}) <- struct{}{}
+	return true
 }
 
-// get prunes stale connections and returns a connection from the idle list or
-// creates a new connection.
-func (p *Pool) get() (Conn, error) {
-	p.mu.Lock()
+// wakeOneLocked wakes the longest-waiting GetContext caller blocked on
+// MaxActive, if any. The caller must hold p.mu during the call.
+func (p *Pool) wakeOneLocked() {
+	wakeFrontLocked(&p.waiters)
+}
 
-	if !p.initialized {
-		p.initialized = true
-		p.nextGCTime = nowFunc().Add(p.GCInterval)
-		log.Printf("mingo : nextgctime %s", p.nextGCTime)
+// wakeOneDialLocked wakes the longest-waiting GetContext caller blocked on
+// the MaxDialsPerSecond token bucket, if any. The caller must hold p.mu
+// during the call.
+func (p *Pool) wakeOneDialLocked() {
+	wakeFrontLocked(&p.dialWaiters)
+}
+
+// wakeAllLocked wakes every waiter queued on the pool, on both waiters and
+// dialWaiters, for use when the pool is being closed. The caller must hold
+// p.mu during the call.
+func (p *Pool) wakeAllLocked() {
+	for wakeFrontLocked(&p.waiters) {
 	}
+	for wakeFrontLocked(&p.dialWaiters) {
+	}
+}
 
-	// Prune stale connections.
-	// 不要频繁的去处理idle conn
-	if timeout := p.IdleTimeout; (timeout > 0) && (nowFunc().After(p.nextGCTime)) {
-		p.nextGCTime = nowFunc().Add(p.GCInterval)
-		log.Printf("mingo : nextgctime %s", p.nextGCTime)
+// takeDialToken reports whether a dial token is available, refilling the
+// bucket based on elapsed time since the last check and consuming one token
+// if so. The bucket's capacity is MaxDialsPerSecond, so a burst can spend at
+// most one second's worth of saved-up dials at once. The caller must hold
+// p.mu during the call.
+func (p *Pool) takeDialToken() bool {
+	if p.MaxDialsPerSecond <= 0 {
+		return true
+	}
 
-		//每次只处理一半的idle conn
-		for i, n := 0, p.idle.Len(); i < int(float32(n)*0.5); i++ {
-			e := p.idle.Back()
-			if e == nil {
-				break
-			}
-			c := e.Value.(Conn)
-			if c.GetIdleTime().Add(timeout).After(nowFunc()) {
-				break
-			}
-			p.idle.Remove(e)
-			p.release()
-			p.mu.Unlock()
-			c.Close()
+	now := nowFunc()
+	if p.lastDial.IsZero() {
+		p.dialTokens = float64(p.MaxDialsPerSecond)
+	} else if elapsed := now.Sub(p.lastDial); elapsed > 0 {
+		p.dialTokens += elapsed.Seconds() * float64(p.MaxDialsPerSecond)
+		if max := float64(p.MaxDialsPerSecond); p.dialTokens > max {
+			p.dialTokens = max
+		}
+	}
+	p.lastDial = now
+
+	if p.dialTokens < 1 {
+		return false
+	}
+	p.dialTokens--
+	return true
+}
+
+// dialRetryInterval is how often the dial notifier goroutine rechecks the
+// dial token bucket on behalf of whichever waiter is at the front of
+// dialWaiters.
+func (p *Pool) dialRetryInterval() time.Duration {
+	if p.MaxDialsPerSecond <= 0 {
+		return time.Millisecond
+	}
+	d := time.Second / time.Duration(p.MaxDialsPerSecond)
+	if d <= 0 {
+		d = time.Millisecond
+	}
+	return d
+}
+
+// startDialNotifierLocked lazily starts a goroutine that periodically wakes
+// the front of dialWaiters so a dial-rate-limited waiter gets a chance to
+// recheck the token bucket, the same way a release does for a MaxActive
+// waiter. Waking only the front of dialWaiters, rather than racing every
+// blocked waiter against a timer of its own, keeps the wait path's fairness
+// consistent with the rest of get() without disturbing plain MaxActive
+// waiters queued on waiters. The caller must hold p.mu.
+func (p *Pool) startDialNotifierLocked() {
+	if p.dialNotifierStarted {
+		return
+	}
+	p.dialNotifierStarted = true
+	p.stopDialNotifier = make(chan struct{})
+	go p.notifyDialWaiters(p.stopDialNotifier)
+}
+
+// notifyDialWaiters runs until stop is closed, waking the front of
+// dialWaiters once per dialRetryInterval.
+func (p *Pool) notifyDialWaiters(stop chan struct{}) {
+	ticker := time.NewTicker(p.dialRetryInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
 			p.mu.Lock()
+			p.wakeOneDialLocked()
+			p.mu.Unlock()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// waitForWake blocks until another goroutine wakes the front of q, or until
+// ctx is done, recording WaitDuration, and WaitCount when countWait is true.
+// q is either &p.waiters or
+// &p.dialWaiters, matching the reason the caller is blocked, so a wakeup
+// meant for one kind of waiter never jumps the other queue. The caller must
+// hold p.mu on entry; waitForWake releases it while blocked and re-acquires
+// it before returning, in both the success and ctx.Done cases.
+func (p *Pool) waitForWake(ctx context.Context, q *list.List, countWait bool) error {
+	// The channel is buffered so that a concurrent wakeFrontLocked never
+	// blocks on us, even if we've already given up by the time it fires.
+	ch := make(chan struct{}, 1)
+	elem := q.PushBack(ch)
+	if countWait {
+		p.waitCount++
+	}
+	p.mu.Unlock()
+
+	waitStart := nowFunc()
+	select {
+	case <-ch:
+		p.mu.Lock()
+		p.waitDuration += nowFunc().Sub(waitStart)
+		return nil
+
+	case <-ctx.Done():
+		p.mu.Lock()
+		p.waitDuration += nowFunc().Sub(waitStart)
+		select {
+		case <-ch:
+			// We were woken just as ctx was canceled; the wakeup is ours to
+			// use, so pass it along to the next waiter on the same queue
+			// instead of losing it.
+			wakeFrontLocked(q)
+		default:
+			q.Remove(elem)
 		}
+		return ctx.Err()
+	}
+}
+
+// get returns a connection from the idle list, closing any that fail
+// TestOnBorrow, or creates a new connection. If the pool is at MaxActive or
+// out of dial tokens and Wait is true, get blocks until it is woken or ctx is
+// done. A call counts once toward WaitCount however many times it blocks.
+func (p *Pool) get(ctx context.Context) (Conn, error) {
+	p.mu.Lock()
+
+	if !p.initialized {
+		p.initialized = true
+		p.startReaperLocked()
 	}
 
+	waited := false
 	for {
 
 		// Get idle connection.
@@ -274,6 +572,7 @@ func (p *Pool) get() (Conn, error) {
 			}
 			c.Close()
 			p.mu.Lock()
+			p.testOnBorrowFailures++
 			p.release()
 		}
 
@@ -287,12 +586,29 @@ func (p *Pool) get() (Conn, error) {
 		// Dial new connection if under limit.
 
 		if p.MaxActive == 0 || p.active < p.MaxActive {
+			if !p.takeDialToken() {
+				if !p.Wait {
+					p.mu.Unlock()
+					return nil, ErrDialRateLimited
+				}
+				p.startDialNotifierLocked()
+				err := p.waitForWake(ctx, &p.dialWaiters, !waited)
+				waited = true
+				if err != nil {
+					p.mu.Unlock()
+					return nil, err
+				}
+				continue
+			}
+
 			dial := p.Dial
 			p.active++
+			p.dialCount++
 			p.mu.Unlock()
 			c, err := dial()
 			if err != nil {
 				p.mu.Lock()
+				p.dialErrorCount++
 				p.release()
 				p.mu.Unlock()
 				c = nil
@@ -305,10 +621,13 @@ func (p *Pool) get() (Conn, error) {
 			return nil, ErrPoolExhausted
 		}
 
-		if p.cond == nil {
-			p.cond = sync.NewCond(&p.mu)
+		err := p.waitForWake(ctx, &p.waiters, !waited)
+		waited = true
+		if err != nil {
+			p.mu.Unlock()
+			return nil, err
 		}
-		p.cond.Wait()
+		// Loop back around to recheck idle connections and active count.
 	}
 }
 
@@ -331,7 +650,7 @@ func (p *Pool) put(c Conn, forceClose bool) error {
 	if !p.closed && err == nil && !forceClose {
 		c.MarkIdleTime()
 		p.idle.PushFront(c)
-		if p.idle.Len() > p.MaxIdle {
+		if p.idle.Len() > p.effectiveMaxIdle() {
 			c = p.idle.Remove(p.idle.Back()).(Conn)
 		} else {
 			c = nil
@@ -339,9 +658,7 @@ func (p *Pool) put(c Conn, forceClose bool) error {
 	}
 
 	if c == nil {
-		if p.cond != nil {
-			p.cond.Signal()
-		}
+		p.wakeOneLocked()
 		p.mu.Unlock()
 		return nil
 	}
@@ -351,16 +668,263 @@ func (p *Pool) put(c Conn, forceClose bool) error {
 	return c.Close()
 }
 
+// pubBatch accumulates messages for one topic while waiting for BatchWindow
+// to elapse or BatchSize to be reached, and the callers waiting on the
+// result of sending them.
+type pubBatch struct {
+	messages [][]byte
+	done     []chan error
+	timer    *time.Timer
+}
+
+// Publish sends msg to topic using a connection from the pool. When
+// BatchWindow is set, concurrent Publish calls for the same topic arriving
+// within the window are coalesced into a single Conn.MPub call; otherwise
+// each call sends its message immediately with Conn.Pub.
+func (p *Pool) Publish(topic string, msg []byte) error {
+	if p.BatchWindow <= 0 {
+		return p.publishBatch(topic, [][]byte{msg})
+	}
+
+	done := make(chan error, 1)
+
+	p.batchMu.Lock()
+	if p.batches == nil {
+		p.batches = make(map[string]*pubBatch)
+	}
+	b := p.batches[topic]
+	if b == nil {
+		b = &pubBatch{}
+		p.batches[topic] = b
+		b.timer = time.AfterFunc(p.BatchWindow, func() { p.flushBatch(topic) })
+	}
+	b.messages = append(b.messages, msg)
+	b.done = append(b.done, done)
+	flushNow := p.BatchSize > 0 && len(b.messages) >= p.BatchSize
+	if flushNow {
+		b.timer.Stop()
+		delete(p.batches, topic)
+	}
+	p.batchMu.Unlock()
+
+	if flushNow {
+		p.sendBatch(topic, b)
+	}
+
+	return <-done
+}
+
+// flushBatch is invoked by a batch's timer once BatchWindow elapses.
+func (p *Pool) flushBatch(topic string) {
+	p.batchMu.Lock()
+	b := p.batches[topic]
+	delete(p.batches, topic)
+	p.batchMu.Unlock()
+
+	if b != nil {
+		p.sendBatch(topic, b)
+	}
+}
+
+// sendBatch publishes a coalesced batch and delivers the result to every
+// caller waiting on it.
+func (p *Pool) sendBatch(topic string, b *pubBatch) {
+	err := p.publishBatch(topic, b.messages)
+	for _, done := range b.done {
+		done <- err
+	}
+}
+
+// publishBatch gets a connection, sends messages to topic as a single
+// MPub when there's more than one, and returns the connection to the pool.
+func (p *Pool) publishBatch(topic string, messages [][]byte) error {
+	c, err := p.GetContext(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if len(messages) == 1 {
+		err = c.Pub(topic, messages[0])
+	} else {
+		err = c.MPub(topic, messages)
+	}
+	p.Put(c, err != nil)
+	return err
+}
+
+// startReaperLocked lazily starts the background reaper goroutine the first
+// time it is needed. The caller must hold p.mu during the call.
+func (p *Pool) startReaperLocked() {
+	if p.reaperStarted {
+		return
+	}
+	if p.MinIdle <= 0 && p.HealthCheckInterval <= 0 && p.IdleTimeout <= 0 {
+		return
+	}
+	p.reaperStarted = true
+	p.stopReaper = make(chan struct{})
+	go p.reap(p.stopReaper)
+}
+
+// reap runs until stop is closed, periodically evicting stale idle
+// connections, proactively pinging connections that have been idle longer
+// than IdlePingTimeout, and dialing ahead to keep MinIdle connections warm.
+func (p *Pool) reap(stop chan struct{}) {
+	interval := p.HealthCheckInterval
+	if interval <= 0 {
+		interval = p.GCInterval
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.evictStaleIdle()
+			p.pingStaleIdle()
+			p.fillMinIdle()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// evictStaleIdle closes idle connections that have exceeded IdleTimeout,
+// working from the back of the idle list (the least recently used end) and
+// holding p.mu only for each individual removal.
+func (p *Pool) evictStaleIdle() {
+	if p.IdleTimeout <= 0 {
+		return
+	}
+	for {
+		p.mu.Lock()
+		e := p.idle.Back()
+		if e == nil {
+			p.mu.Unlock()
+			return
+		}
+		c := e.Value.(Conn)
+		if c.GetIdleTime().Add(p.IdleTimeout).After(nowFunc()) {
+			p.mu.Unlock()
+			return
+		}
+		p.idle.Remove(e)
+		p.timeoutCount++
+		p.release()
+		p.mu.Unlock()
+		c.Close()
+	}
+}
+
+// pingStaleIdle calls TestOnBorrow on idle connections older than
+// IdlePingTimeout, closing any that fail the check.
+func (p *Pool) pingStaleIdle() {
+	test := p.TestOnBorrow
+	if test == nil || p.IdlePingTimeout <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	var stale []Conn
+	for e := p.idle.Front(); e != nil; e = e.Next() {
+		c := e.Value.(Conn)
+		if nowFunc().Sub(c.GetIdleTime()) >= p.IdlePingTimeout {
+			stale = append(stale, c)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, c := range stale {
+		if err := test(c, c.GetIdleTime()); err != nil {
+			p.mu.Lock()
+			p.testOnBorrowFailures++
+			p.removeIdleLocked(c)
+			p.mu.Unlock()
+			c.Close()
+			continue
+		}
+		p.mu.Lock()
+		c.MarkIdleTime()
+		p.mu.Unlock()
+	}
+}
+
+// removeIdleLocked removes c from the idle list, if still present, and
+// releases its active slot. The caller must hold p.mu during the call.
+func (p *Pool) removeIdleLocked(c Conn) {
+	for e := p.idle.Front(); e != nil; e = e.Next() {
+		if e.Value.(Conn) == c {
+			p.idle.Remove(e)
+			p.release()
+			return
+		}
+	}
+}
+
+// effectiveMaxIdle returns the idle-list cap put() enforces: MaxIdle, bumped
+// up to MinIdle if the latter is larger. Without this, a MinIdle warm pool
+// configured past a smaller (or zero-value default) MaxIdle would have put()
+// evict every connection fillMinIdle just dialed, so the idle count could
+// never reach MinIdle and fillMinIdle would dial and close forever.
+func (p *Pool) effectiveMaxIdle() int {
+	if p.MinIdle > p.MaxIdle {
+		return p.MinIdle
+	}
+	return p.MaxIdle
+}
+
+// fillMinIdle dials new connections, one at a time, until IdleCount reaches
+// MinIdle or the pool hits MaxActive or is closed.
+func (p *Pool) fillMinIdle() {
+	if p.MinIdle <= 0 {
+		return
+	}
+	for {
+		p.mu.Lock()
+		if p.closed || p.idle.Len() >= p.MinIdle {
+			p.mu.Unlock()
+			return
+		}
+		if p.MaxActive != 0 && p.active >= p.MaxActive {
+			p.mu.Unlock()
+			return
+		}
+		if !p.takeDialToken() {
+			// Leave warming up idle connections to future reaper ticks
+			// rather than burning the budget meant for foreground Get/
+			// GetContext callers.
+			p.mu.Unlock()
+			return
+		}
+		dial := p.Dial
+		p.active++
+		p.dialCount++
+		p.mu.Unlock()
+
+		c, err := dial()
+		if err != nil {
+			p.mu.Lock()
+			p.dialErrorCount++
+			p.release()
+			p.mu.Unlock()
+			return
+		}
+		p.put(c, false)
+	}
+}
+
 type errorConnection struct {
 	err      error
 	idleTime time.Time
 }
 
-func (ec errorConnection) Post([]byte, ...interface{}) (interface{}, error) { return nil, ec.err }
-func (ec errorConnection) Send([]byte, ...interface{}) error                { return ec.err }
 func (ec errorConnection) Err() error                                       { return ec.err }
 func (ec errorConnection) Close() error                                     { return ec.err }
-func (ec errorConnection) Flush() error                                     { return ec.err }
-func (ec errorConnection) Receive() (interface{}, error)                    { return nil, ec.err }
+func (ec errorConnection) Pub(string, []byte) error                        { return ec.err }
+func (ec errorConnection) MPub(string, [][]byte) error                     { return ec.err }
 func (ec errorConnection) MarkIdleTime()                                    { ec.idleTime = nowFunc() }
 func (ec errorConnection) GetIdleTime() time.Time                           { return ec.idleTime }
@@ -0,0 +1,89 @@
+package mingo
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConn is a minimal Conn used by pool tests that don't need a real
+// network connection.
+type fakeConn struct {
+	mu       sync.Mutex
+	closed   bool
+	idleTime time.Time
+}
+
+func (c *fakeConn) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *fakeConn) Err() error                  { return nil }
+func (c *fakeConn) Pub(string, []byte) error    { return nil }
+func (c *fakeConn) MPub(string, [][]byte) error { return nil }
+
+func (c *fakeConn) MarkIdleTime() {
+	c.mu.Lock()
+	c.idleTime = time.Now()
+	c.mu.Unlock()
+}
+
+func (c *fakeConn) GetIdleTime() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.idleTime
+}
+
+func TestGetContextCancelDoesNotLeakActiveOrWaiters(t *testing.T) {
+	dialCount := 0
+	p := &Pool{
+		MaxActive: 1,
+		MaxIdle:   1,
+		Wait:      true,
+		Dial: func() (Conn, error) {
+			dialCount++
+			return &fakeConn{}, nil
+		},
+	}
+
+	first, err := p.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("first GetContext: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := p.GetContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("GetContext on full pool = %v, want context.DeadlineExceeded", err)
+	}
+
+	if active := p.ActiveCount(); active != 1 {
+		t.Fatalf("ActiveCount leaked by canceled waiter: got %d, want 1", active)
+	}
+
+	p.mu.Lock()
+	waiting := p.waiters.Len()
+	p.mu.Unlock()
+	if waiting != 0 {
+		t.Fatalf("canceled waiter left in queue: %d entries remain", waiting)
+	}
+
+	if err := p.Put(first, false); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	second, err := p.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("GetContext after release: %v", err)
+	}
+	defer second.Close()
+
+	if dialCount != 1 {
+		t.Fatalf("dialCount = %d, want 1 (the second GetContext must reuse the connection Put back)", dialCount)
+	}
+}
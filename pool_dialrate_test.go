@@ -0,0 +1,55 @@
+package mingo
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDialRateLimitedReturnsErrDialRateLimitedWithoutWait(t *testing.T) {
+	p := &Pool{
+		MaxDialsPerSecond: 1,
+		Dial: func() (Conn, error) {
+			return &fakeConn{}, nil
+		},
+	}
+	defer p.Close()
+
+	first, err := p.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("first GetContext: %v", err)
+	}
+	defer first.Close()
+
+	if _, err := p.GetContext(context.Background()); err != ErrDialRateLimited {
+		t.Fatalf("second GetContext = %v, want ErrDialRateLimited", err)
+	}
+}
+
+// TestDialNotifierWakesOnlyDialWaitersNotMaxActiveWaiters regresses a bug
+// where the dial notifier woke the front of the same FIFO queue used by
+// plain MaxActive waiters: a tick landing on a MaxActive waiter woke it for
+// no reason, and if it found no connection available it re-queued itself
+// behind later arrivals, breaking the waiters queue's FIFO guarantee.
+// dialWaiters and waiters must stay fully independent.
+func TestDialNotifierWakesOnlyDialWaitersNotMaxActiveWaiters(t *testing.T) {
+	p := &Pool{}
+
+	p.mu.Lock()
+	maxActiveCh := make(chan struct{}, 1)
+	p.waiters.PushBack(maxActiveCh)
+	dialCh := make(chan struct{}, 1)
+	p.dialWaiters.PushBack(dialCh)
+	p.wakeOneDialLocked()
+	p.mu.Unlock()
+
+	select {
+	case <-dialCh:
+	default:
+		t.Fatal("wakeOneDialLocked did not wake the dial waiter")
+	}
+	select {
+	case <-maxActiveCh:
+		t.Fatal("wakeOneDialLocked woke a MaxActive waiter queued on the unrelated waiters queue")
+	default:
+	}
+}
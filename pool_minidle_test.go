@@ -0,0 +1,25 @@
+package mingo
+
+import "testing"
+
+func TestFillMinIdleRespectsMinIdleOverMaxIdle(t *testing.T) {
+	dialCount := 0
+	p := &Pool{
+		MinIdle: 3,
+		// MaxIdle intentionally left at its zero value: a foreseeable
+		// config where the warm pool target exceeds the idle cap.
+		Dial: func() (Conn, error) {
+			dialCount++
+			return &fakeConn{}, nil
+		},
+	}
+
+	p.fillMinIdle()
+
+	if idle := p.IdleCount(); idle != p.MinIdle {
+		t.Fatalf("IdleCount = %d, want MinIdle (%d)", idle, p.MinIdle)
+	}
+	if dialCount != p.MinIdle {
+		t.Fatalf("dialCount = %d, want %d; fillMinIdle must stop once MinIdle idle conns are kept, not dial-storm because MaxIdle < MinIdle keeps evicting them", dialCount, p.MinIdle)
+	}
+}
@@ -0,0 +1,146 @@
+package mingo
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingConn records every Pub/MPub call it receives, for tests that
+// assert on how Publish batches messages.
+type recordingConn struct {
+	mu       sync.Mutex
+	idleTime time.Time
+	pubs     [][]byte
+	mpubs    [][][]byte
+}
+
+func (c *recordingConn) Close() error { return nil }
+func (c *recordingConn) Err() error   { return nil }
+
+func (c *recordingConn) Pub(topic string, message []byte) error {
+	c.mu.Lock()
+	c.pubs = append(c.pubs, message)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *recordingConn) MPub(topic string, messages [][]byte) error {
+	c.mu.Lock()
+	c.mpubs = append(c.mpubs, messages)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *recordingConn) MarkIdleTime() {
+	c.mu.Lock()
+	c.idleTime = time.Now()
+	c.mu.Unlock()
+}
+
+func (c *recordingConn) GetIdleTime() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.idleTime
+}
+
+func TestPublishBatchesConcurrentCallsWithinWindow(t *testing.T) {
+	conn := &recordingConn{}
+	p := &Pool{
+		MaxActive:   1,
+		MaxIdle:     1,
+		BatchWindow: 50 * time.Millisecond,
+		Dial:        func() (Conn, error) { return conn, nil },
+	}
+	defer p.Close()
+
+	const n = 5
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = p.Publish("topic", []byte{byte(i)})
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Publish(%d): %v", i, err)
+		}
+	}
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if len(conn.pubs) != 0 {
+		t.Fatalf("Pub called %d times, want 0 (concurrent Publish calls should have coalesced into one MPub)", len(conn.pubs))
+	}
+	if len(conn.mpubs) != 1 {
+		t.Fatalf("MPub called %d times, want 1", len(conn.mpubs))
+	}
+	if got := len(conn.mpubs[0]); got != n {
+		t.Fatalf("MPub got %d messages, want %d", got, n)
+	}
+}
+
+func TestPublishFlushesEarlyAtBatchSize(t *testing.T) {
+	conn := &recordingConn{}
+	p := &Pool{
+		MaxActive:   1,
+		MaxIdle:     1,
+		BatchWindow: time.Hour, // long enough that only BatchSize should force a flush
+		BatchSize:   2,
+		Dial:        func() (Conn, error) { return conn, nil },
+	}
+	defer p.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := p.Publish("topic", []byte{byte(i)}); err != nil {
+				t.Errorf("Publish(%d): %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if len(conn.mpubs) != 1 || len(conn.mpubs[0]) != 2 {
+		t.Fatalf("mpubs = %v, want one call with 2 messages", conn.mpubs)
+	}
+}
+
+func TestCloseCancelsPendingBatch(t *testing.T) {
+	conn := &recordingConn{}
+	p := &Pool{
+		MaxActive:   1,
+		BatchWindow: time.Hour,
+		Dial:        func() (Conn, error) { return conn, nil },
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Publish("topic", []byte("msg"))
+	}()
+
+	// Give Publish a moment to register its batch before Close runs.
+	time.Sleep(10 * time.Millisecond)
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != errPoolClosed {
+			t.Fatalf("Publish returned %v, want errPoolClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Publish still blocked a second after Close; pending batch was not canceled")
+	}
+}
@@ -0,0 +1,49 @@
+package mingo
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStatsReportsCumulativeCounters(t *testing.T) {
+	dialErr := errors.New("dial failed")
+	fail := true
+	p := &Pool{
+		MaxIdle: 1,
+		Dial: func() (Conn, error) {
+			if fail {
+				fail = false
+				return nil, dialErr
+			}
+			return &fakeConn{}, nil
+		},
+	}
+	defer p.Close()
+
+	if _, err := p.GetContext(context.Background()); err != dialErr {
+		t.Fatalf("first GetContext = %v, want %v", err, dialErr)
+	}
+
+	c, err := p.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("second GetContext: %v", err)
+	}
+	if err := p.Put(c, false); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	stats := p.Stats()
+	if stats.DialCount != 2 {
+		t.Errorf("DialCount = %d, want 2", stats.DialCount)
+	}
+	if stats.DialErrorCount != 1 {
+		t.Errorf("DialErrorCount = %d, want 1", stats.DialErrorCount)
+	}
+	if stats.ActiveCount != 1 {
+		t.Errorf("ActiveCount = %d, want 1 (the connection is idle, not closed)", stats.ActiveCount)
+	}
+	if stats.IdleCount != 1 {
+		t.Errorf("IdleCount = %d, want 1", stats.IdleCount)
+	}
+}